@@ -0,0 +1,121 @@
+/*
+Copyright 2019 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package livestate
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+var errNotSynced = errors.New("livestate: informer caches did not sync")
+
+// manifestIndex holds the manifests Skaffold rendered and applied for this
+// run, keyed by kind/namespace/name, so live objects can be diffed against
+// the spec that was actually deployed.
+type manifestIndex map[string]*unstructured.Unstructured
+
+func newManifestIndex(rendered []*unstructured.Unstructured) manifestIndex {
+	idx := make(manifestIndex, len(rendered))
+	for _, u := range rendered {
+		idx[manifestKey(u.GetKind(), u.GetNamespace(), u.GetName())] = u
+	}
+	return idx
+}
+
+func manifestKey(kind, ns, name string) string {
+	return fmt.Sprintf("%s/%s/%s", kind, ns, name)
+}
+
+// synced reports whether live's spec matches the manifest Skaffold rendered
+// for this resource. The API server and admission/defaulting add a large,
+// version-dependent set of fields that never appear in a rendered manifest
+// (status, metadata.uid, spec.template.spec.dnsPolicy, container
+// imagePullPolicy, ...); rather than enumerate them, live is projected down
+// to only the fields the rendered manifest actually sets before comparing,
+// so defaulted fields can never be mistaken for drift. If the resource isn't
+// tracked by a rendered manifest at all, it is reported as synced: Skaffold
+// has nothing to compare it against.
+func (idx manifestIndex) synced(kind, ns, name string, live runtime.Object) (bool, string) {
+	rendered, ok := idx[manifestKey(kind, ns, name)]
+	if !ok {
+		return true, ""
+	}
+
+	liveUn, err := toUnstructured(live)
+	if err != nil {
+		return true, ""
+	}
+	// Typed objects read through the clientset carry an empty TypeMeta, and
+	// the index key has already matched the kind, so apiVersion and kind are
+	// taken from the rendered manifest instead of being reported as drift.
+	liveUn.SetAPIVersion(rendered.GetAPIVersion())
+	liveUn.SetKind(rendered.GetKind())
+
+	projected := projectOntoRendered(liveUn.Object, rendered.Object)
+	if reflect.DeepEqual(projected, rendered.Object) {
+		return true, ""
+	}
+	return false, "live spec differs from the rendered manifest"
+}
+
+func toUnstructured(obj runtime.Object) (*unstructured.Unstructured, error) {
+	m, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	return &unstructured.Unstructured{Object: m}, nil
+}
+
+// projectOntoRendered returns the subset of live that occupies the same
+// shape as rendered: every map key and slice index rendered doesn't have is
+// dropped, recursively, so only fields Skaffold actually authored are kept.
+// Anything rendered sets that live is missing is left absent, so it still
+// shows up as a difference once compared.
+func projectOntoRendered(live, rendered interface{}) interface{} {
+	switch renderedVal := rendered.(type) {
+	case map[string]interface{}:
+		liveMap, ok := live.(map[string]interface{})
+		if !ok {
+			return live
+		}
+		out := make(map[string]interface{}, len(renderedVal))
+		for k, rv := range renderedVal {
+			if lv, ok := liveMap[k]; ok {
+				out[k] = projectOntoRendered(lv, rv)
+			}
+		}
+		return out
+	case []interface{}:
+		liveSlice, ok := live.([]interface{})
+		if !ok {
+			return live
+		}
+		out := make([]interface{}, len(renderedVal))
+		for i, rv := range renderedVal {
+			if i < len(liveSlice) {
+				out[i] = projectOntoRendered(liveSlice[i], rv)
+			}
+		}
+		return out
+	default:
+		return live
+	}
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2019 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package livestate
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	utilpointer "k8s.io/utils/pointer"
+
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func intOrStringPtr(v intstr.IntOrString) *intstr.IntOrString {
+	return &v
+}
+
+func TestManifestIndexSynced(t *testing.T) {
+	rendered := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]interface{}{
+			"name":      "web",
+			"namespace": "test",
+		},
+		"spec": map[string]interface{}{
+			"replicas": int64(3),
+		},
+	}}
+	idx := newManifestIndex([]*unstructured.Unstructured{rendered})
+
+	tests := []struct {
+		description string
+		live        *appsv1.Deployment
+		expected    bool
+	}{
+		{
+			description: "matching spec, differing server-populated fields",
+			live: &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "web", Namespace: "test",
+					UID: "abc-123", ResourceVersion: "42", Generation: 7,
+				},
+				Spec: appsv1.DeploymentSpec{Replicas: utilpointer.Int32Ptr(3)},
+			},
+			expected: true,
+		},
+		{
+			description: "spec edited out from under skaffold",
+			live: &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "test"},
+				Spec:       appsv1.DeploymentSpec{Replicas: utilpointer.Int32Ptr(5)},
+			},
+			expected: false,
+		},
+		{
+			description: "matching spec, apiserver-defaulted fields the manifest never set",
+			live: &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "test"},
+				Spec: appsv1.DeploymentSpec{
+					Replicas:                utilpointer.Int32Ptr(3),
+					RevisionHistoryLimit:    utilpointer.Int32Ptr(10),
+					ProgressDeadlineSeconds: utilpointer.Int32Ptr(600),
+					Strategy: appsv1.DeploymentStrategy{
+						Type: appsv1.RollingUpdateDeploymentStrategyType,
+						RollingUpdate: &appsv1.RollingUpdateDeployment{
+							MaxSurge:       intOrStringPtr(intstr.FromString("25%")),
+							MaxUnavailable: intOrStringPtr(intstr.FromString("25%")),
+						},
+					},
+				},
+			},
+			expected: true,
+		},
+	}
+
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			synced, _ := idx.synced("Deployment", "test", "web", test.live)
+			t.CheckDeepEqual(test.expected, synced)
+		})
+	}
+}
+
+func TestManifestIndexSyncedUntrackedResource(t *testing.T) {
+	idx := newManifestIndex(nil)
+
+	synced, reason := idx.synced("Deployment", "test", "web", &appsv1.Deployment{})
+	if !synced || reason != "" {
+		t.Errorf("expected untracked resource to be reported synced, got synced=%v reason=%q", synced, reason)
+	}
+}
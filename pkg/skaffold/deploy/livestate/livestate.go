@@ -0,0 +1,270 @@
+/*
+Copyright 2019 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package livestate keeps watching the resources a `skaffold dev` session
+// deployed after the initial rollout completes, so the CLI and the event API
+// can surface drift and health changes without waiting for the next
+// sync/build cycle.
+package livestate
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy"
+)
+
+// resyncPeriod is how often the informers underlying the Reporter do a full
+// relist, to catch any updates missed by a dropped watch.
+const resyncPeriod = 30 * time.Second
+
+// Event describes a change in the live state of a single resource Skaffold
+// deployed. It is consumed by the event API (proto.Event) to drive the CLI
+// and editor-facing status stream.
+type Event struct {
+	Kind      string
+	Namespace string
+	Name      string
+	Healthy   bool
+	Synced    bool
+	Reason    string
+}
+
+// Reporter watches every resource tagged with a run ID and emits an Event
+// carrying its health and its drift from the rendered manifest each time an
+// informer reports it added or updated, periodic resyncs included. Events
+// are not deduplicated: one is emitted even when nothing has changed.
+type Reporter struct {
+	client    kubernetes.Interface
+	ns        string
+	labeller  *deploy.Labeller
+	manifests manifestIndex
+	events    chan Event
+	inFlight  sync.WaitGroup
+}
+
+// NewReporter creates a Reporter for the resources rendered in this run.
+// rendered are the manifests Skaffold applied, used as the source of truth
+// when detecting drift.
+func NewReporter(client kubernetes.Interface, ns string, labeller *deploy.Labeller, rendered []*unstructured.Unstructured) *Reporter {
+	return &Reporter{
+		client:    client,
+		ns:        ns,
+		labeller:  labeller,
+		manifests: newManifestIndex(rendered),
+		events:    make(chan Event),
+	}
+}
+
+// Events returns the channel Event values are emitted on. It is closed once
+// the context passed to Start is cancelled.
+func (r *Reporter) Events() <-chan Event {
+	return r.events
+}
+
+// Start registers informers for every resource kind the status checker
+// understands, scoped to this run's resources, and begins emitting an Event
+// for every add and update the informers observe. It blocks until the
+// informer caches are synced.
+func (r *Reporter) Start(ctx context.Context) error {
+	factory := informers.NewSharedInformerFactoryWithOptions(
+		r.client,
+		resyncPeriod,
+		informers.WithNamespace(r.ns),
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.LabelSelector = r.labeller.RunIDSelector()
+		}),
+	)
+
+	handlers := cache.ResourceEventHandlerFuncs{
+		AddFunc:    r.handle,
+		UpdateFunc: func(_, obj interface{}) { r.handle(obj) },
+	}
+
+	factory.Apps().V1().Deployments().Informer().AddEventHandler(handlers)
+	factory.Apps().V1().StatefulSets().Informer().AddEventHandler(handlers)
+	factory.Apps().V1().DaemonSets().Informer().AddEventHandler(handlers)
+	factory.Apps().V1().ReplicaSets().Informer().AddEventHandler(handlers)
+	factory.Core().V1().ReplicationControllers().Informer().AddEventHandler(handlers)
+	factory.Core().V1().Pods().Informer().AddEventHandler(handlers)
+	factory.Core().V1().Services().Informer().AddEventHandler(handlers)
+	factory.Core().V1().PersistentVolumeClaims().Informer().AddEventHandler(handlers)
+	factory.Batch().V1().Jobs().Informer().AddEventHandler(handlers)
+
+	factory.Start(ctx.Done())
+	synced := factory.WaitForCacheSync(ctx.Done())
+	for _, ok := range synced {
+		if !ok {
+			return errNotSynced
+		}
+	}
+
+	go func() {
+		<-ctx.Done()
+		// Wait for every in-flight handle() to finish its send before
+		// closing events, otherwise a handler racing this goroutine could
+		// send on a closed channel and panic.
+		r.inFlight.Wait()
+		close(r.events)
+	}()
+
+	return nil
+}
+
+func (r *Reporter) handle(obj interface{}) {
+	r.inFlight.Add(1)
+	defer r.inFlight.Done()
+
+	ro, ok := obj.(runtime.Object)
+	if !ok {
+		return
+	}
+
+	kind, ns, name := kindNamespaceName(ro)
+	healthy, reason := health(ro)
+	synced, syncReason := r.manifests.synced(kind, ns, name, ro)
+	if !synced && reason == "" {
+		reason = syncReason
+	}
+
+	r.events <- Event{
+		Kind:      kind,
+		Namespace: ns,
+		Name:      name,
+		Healthy:   healthy,
+		Synced:    synced,
+		Reason:    reason,
+	}
+}
+
+func kindNamespaceName(obj runtime.Object) (kind, ns, name string) {
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		return "Deployment", o.Namespace, o.Name
+	case *appsv1.StatefulSet:
+		return "StatefulSet", o.Namespace, o.Name
+	case *appsv1.DaemonSet:
+		return "DaemonSet", o.Namespace, o.Name
+	case *appsv1.ReplicaSet:
+		return "ReplicaSet", o.Namespace, o.Name
+	case *corev1.ReplicationController:
+		return "ReplicationController", o.Namespace, o.Name
+	case *corev1.Pod:
+		return "Pod", o.Namespace, o.Name
+	case *corev1.Service:
+		return "Service", o.Namespace, o.Name
+	case *corev1.PersistentVolumeClaim:
+		return "PersistentVolumeClaim", o.Namespace, o.Name
+	case *batchv1.Job:
+		return "Job", o.Namespace, o.Name
+	default:
+		return "", "", ""
+	}
+}
+
+// health runs the same per-kind readiness predicate status-check uses, and
+// classifies a resource as unhealthy if it has entered a known terminal
+// failure state even while technically still "progressing".
+func health(obj runtime.Object) (bool, string) {
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		if deploy.DeploymentReady(o, false) {
+			return true, ""
+		}
+		return false, "rollout not yet complete"
+	case *appsv1.StatefulSet:
+		if deploy.StatefulSetReady(o) {
+			return true, ""
+		}
+		return false, "rollout not yet complete"
+	case *appsv1.DaemonSet:
+		if deploy.DaemonSetReady(o) {
+			return true, ""
+		}
+		return false, "rollout not yet complete"
+	case *appsv1.ReplicaSet:
+		if deploy.ReplicaSetReady(o) {
+			return true, ""
+		}
+		return false, "rollout not yet complete"
+	case *corev1.ReplicationController:
+		if deploy.ReplicationControllerReady(o) {
+			return true, ""
+		}
+		return false, "rollout not yet complete"
+	case *corev1.Pod:
+		if reason := podFailureReason(o); reason != "" {
+			return false, reason
+		}
+		if deploy.PodReady(o) {
+			return true, ""
+		}
+		return false, "pod not yet ready"
+	case *corev1.Service:
+		if deploy.ServiceReady(o) {
+			return true, ""
+		}
+		return false, "waiting for load balancer ingress"
+	case *corev1.PersistentVolumeClaim:
+		if o.Status.Phase == corev1.ClaimLost {
+			return false, "persistentvolumeclaim lost its volume"
+		}
+		if o.Status.Phase == corev1.ClaimBound {
+			return true, ""
+		}
+		return false, "waiting to be bound"
+	case *batchv1.Job:
+		if deploy.JobReady(o) {
+			return true, ""
+		}
+		if o.Status.Failed > 0 {
+			return false, "job has failed pods"
+		}
+		return false, "job not yet complete"
+	default:
+		return true, ""
+	}
+}
+
+// podFailureReason inspects a pod's container statuses for the classic
+// terminal failure states that should be surfaced immediately rather than
+// reported as merely "not ready yet".
+func podFailureReason(p *corev1.Pod) string {
+	for _, cs := range p.Status.ContainerStatuses {
+		if cs.State.Waiting == nil {
+			continue
+		}
+		switch cs.State.Waiting.Reason {
+		case "ImagePullBackOff", "ErrImagePull", "CrashLoopBackOff":
+			return cs.State.Waiting.Reason
+		}
+	}
+	if p.Status.Phase == corev1.PodFailed {
+		return "Failed"
+	}
+	return ""
+}
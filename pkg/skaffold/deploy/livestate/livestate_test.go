@@ -0,0 +1,162 @@
+/*
+Copyright 2019 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package livestate
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	fakekubeclientset "k8s.io/client-go/kubernetes/fake"
+	utilpointer "k8s.io/utils/pointer"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+// TestWatchAfterStatusCheck exercises the hand-off skaffold dev's main loop
+// is expected to make: status-check runs first, and once it succeeds a
+// Reporter keeps watching the same resources, closing its event channel
+// once the loop cancels ctx.
+func TestWatchAfterStatusCheck(t *testing.T) {
+	labeller := deploy.NewLabeller("")
+	client := fakekubeclientset.NewSimpleClientset()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := WatchAfterStatusCheck(ctx, client, nil, "test", labeller, deploy.StatusCheckOptions{Deadline: time.Second}, nil, new(bytes.Buffer))
+	if err != nil {
+		t.Fatalf("WatchAfterStatusCheck: %v", err)
+	}
+
+	cancel()
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Fatal("expected events channel to be closed")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for events channel to close")
+	}
+}
+
+func TestReporterEmitsEvents(t *testing.T) {
+	labeller := deploy.NewLabeller("")
+	runID := runIDValue(labeller)
+
+	tests := []struct {
+		description string
+		dep         *appsv1.Deployment
+		rendered    *unstructured.Unstructured
+		expected    Event
+	}{
+		{
+			description: "healthy and in sync with the rendered manifest",
+			dep:         readyLabelledDeployment("web", runID, 3),
+			rendered:    renderedDeployment("web", 3),
+			expected:    Event{Kind: "Deployment", Namespace: "test", Name: "web", Healthy: true, Synced: true},
+		},
+		{
+			description: "healthy but drifted from the rendered manifest",
+			dep:         readyLabelledDeployment("web", runID, 5),
+			rendered:    renderedDeployment("web", 3),
+			expected:    Event{Kind: "Deployment", Namespace: "test", Name: "web", Healthy: true, Synced: false, Reason: "live spec differs from the rendered manifest"},
+		},
+	}
+
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			client := fakekubeclientset.NewSimpleClientset(test.dep)
+			r := NewReporter(client, "test", labeller, []*unstructured.Unstructured{test.rendered})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			if err := r.Start(ctx); err != nil {
+				t.Fatalf("Start: %v", err)
+			}
+
+			select {
+			case event := <-r.Events():
+				t.CheckDeepEqual(test.expected, event)
+			case <-time.After(5 * time.Second):
+				t.Fatal("timed out waiting for event")
+			}
+		})
+	}
+}
+
+func TestReporterClosesEventsOnCancel(t *testing.T) {
+	labeller := deploy.NewLabeller("")
+	client := fakekubeclientset.NewSimpleClientset()
+	r := NewReporter(client, "test", labeller, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := r.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	cancel()
+
+	select {
+	case _, ok := <-r.Events():
+		if ok {
+			t.Fatal("expected events channel to be closed")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for events channel to close")
+	}
+}
+
+func runIDValue(l *deploy.Labeller) string {
+	parts := strings.SplitN(l.RunIDSelector(), "=", 2)
+	return parts[1]
+}
+
+func readyLabelledDeployment(name, runID string, replicas int32) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name, Namespace: "test", Generation: 1,
+			Labels: map[string]string{deploy.RunIDLabel: runID},
+		},
+		Spec: appsv1.DeploymentSpec{Replicas: utilpointer.Int32Ptr(replicas)},
+		Status: appsv1.DeploymentStatus{
+			ObservedGeneration: 1,
+			UpdatedReplicas:    replicas,
+			AvailableReplicas:  replicas,
+		},
+	}
+}
+
+func renderedDeployment(name string, replicas int64) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": "test",
+		},
+		"spec": map[string]interface{}{
+			"replicas": replicas,
+		},
+	}}
+}
@@ -0,0 +1,47 @@
+/*
+Copyright 2019 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package livestate
+
+import (
+	"context"
+	"io"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubectl"
+)
+
+// WatchAfterStatusCheck runs status-check to wait for the resources Skaffold
+// just deployed to stabilize, then hands off to a Reporter that keeps
+// watching those same resources for drift and health changes for the rest
+// of the `skaffold dev` session. It is the intended call site for the dev
+// loop: once status-check succeeds, the dev loop should range over the
+// returned channel and forward each Event to the CLI and the event API for
+// the remainder of the session, stopping when ctx is cancelled.
+func WatchAfterStatusCheck(ctx context.Context, client kubernetes.Interface, cli *kubectl.CLI, ns string, labeller *deploy.Labeller, opts deploy.StatusCheckOptions, rendered []*unstructured.Unstructured, out io.Writer) (<-chan Event, error) {
+	if err := deploy.StatusCheck(ctx, client, cli, ns, labeller, opts, out); err != nil {
+		return nil, err
+	}
+
+	r := NewReporter(client, ns, labeller, rendered)
+	if err := r.Start(ctx); err != nil {
+		return nil, err
+	}
+	return r.Events(), nil
+}
@@ -0,0 +1,430 @@
+/*
+Copyright 2019 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Resource is a single Kubernetes object that Skaffold deployed and that
+// status-check should wait to become ready, e.g. a Deployment or a Service.
+type Resource interface {
+	// String returns the kind-qualified name of the resource, e.g. "deployment/web".
+	String() string
+	// Deadline is how long status-check should wait for this resource to
+	// become ready before giving up.
+	Deadline() time.Duration
+	// Ready reports whether the resource has reached a healthy, stable state.
+	Ready(ctx context.Context) (bool, error)
+	// Watch opens a watch scoped to just this resource, for the watch-based
+	// status-check engine.
+	Watch() (watch.Interface, error)
+}
+
+// nameFieldSelector scopes a watch/list to a single resource by name.
+func nameFieldSelector(name string) metav1.ListOptions {
+	return metav1.ListOptions{FieldSelector: fields.OneTermEqualSelector("metadata.name", name).String()}
+}
+
+// readyAndMessage evaluates the per-kind Ready() predicate against a typed
+// object observed from a watch event or a Get, and synthesizes a
+// human-friendly progress message for it, mirroring the strings
+// `kubectl rollout status` prints.
+func readyAndMessage(obj runtime.Object, strict bool) (bool, string, error) {
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		replicas := int32(1)
+		if o.Spec.Replicas != nil {
+			replicas = *o.Spec.Replicas
+		}
+		if DeploymentReady(o, strict) {
+			return true, fmt.Sprintf("deployment/%s successfully rolled out", o.Name), nil
+		}
+		return false, fmt.Sprintf("Waiting for rollout to finish: %d of %d updated replicas are available...", o.Status.AvailableReplicas, replicas), nil
+	case *appsv1.StatefulSet:
+		replicas := int32(1)
+		if o.Spec.Replicas != nil {
+			replicas = *o.Spec.Replicas
+		}
+		if StatefulSetReady(o) {
+			return true, fmt.Sprintf("statefulset/%s successfully rolled out", o.Name), nil
+		}
+		return false, fmt.Sprintf("Waiting for rollout to finish: %d of %d pods ready...", o.Status.ReadyReplicas, replicas), nil
+	case *appsv1.DaemonSet:
+		if DaemonSetReady(o) {
+			return true, fmt.Sprintf("daemonset/%s successfully rolled out", o.Name), nil
+		}
+		return false, fmt.Sprintf("Waiting for rollout to finish: %d of %d updated pods are available...", o.Status.NumberReady, o.Status.DesiredNumberScheduled), nil
+	case *corev1.Pod:
+		if PodReady(o) {
+			return true, fmt.Sprintf("pod/%s is ready", o.Name), nil
+		}
+		return false, fmt.Sprintf("Waiting for pod/%s to be ready...", o.Name), nil
+	case *corev1.Service:
+		if ServiceReady(o) {
+			return true, fmt.Sprintf("service/%s is ready", o.Name), nil
+		}
+		return false, fmt.Sprintf("Waiting for service/%s to get a load balancer ingress...", o.Name), nil
+	case *corev1.PersistentVolumeClaim:
+		if o.Status.Phase == corev1.ClaimBound {
+			return true, fmt.Sprintf("persistentvolumeclaim/%s is bound", o.Name), nil
+		}
+		return false, fmt.Sprintf("Waiting for persistentvolumeclaim/%s to be bound...", o.Name), nil
+	case *batchv1.Job:
+		if JobReady(o) {
+			return true, fmt.Sprintf("job/%s completed", o.Name), nil
+		}
+		return false, fmt.Sprintf("Waiting for job/%s to complete: %d succeeded...", o.Name, o.Status.Succeeded), nil
+	case *appsv1.ReplicaSet:
+		replicas := int32(1)
+		if o.Spec.Replicas != nil {
+			replicas = *o.Spec.Replicas
+		}
+		if ReplicaSetReady(o) {
+			return true, fmt.Sprintf("replicaset/%s successfully rolled out", o.Name), nil
+		}
+		return false, fmt.Sprintf("Waiting for rollout to finish: %d of %d pods ready...", o.Status.ReadyReplicas, replicas), nil
+	case *corev1.ReplicationController:
+		replicas := int32(1)
+		if o.Spec.Replicas != nil {
+			replicas = *o.Spec.Replicas
+		}
+		if ReplicationControllerReady(o) {
+			return true, fmt.Sprintf("replicationcontroller/%s successfully rolled out", o.Name), nil
+		}
+		return false, fmt.Sprintf("Waiting for rollout to finish: %d of %d pods ready...", o.Status.ReadyReplicas, replicas), nil
+	default:
+		return false, "", fmt.Errorf("unsupported resource type %T", obj)
+	}
+}
+
+type deploymentResource struct {
+	client   kubernetes.Interface
+	ns       string
+	name     string
+	deadline time.Duration
+	// strict requires every replica to be updated and available before the
+	// deployment is considered ready, ignoring maxUnavailable. Corresponds
+	// to the statusCheck.strictReplicas config knob.
+	strict bool
+}
+
+func (r *deploymentResource) String() string { return fmt.Sprintf("deployment/%s", r.name) }
+func (r *deploymentResource) Deadline() time.Duration { return r.deadline }
+
+func (r *deploymentResource) Ready(ctx context.Context) (bool, error) {
+	d, err := r.client.AppsV1().Deployments(r.ns).Get(r.name, metav1.GetOptions{})
+	if err != nil {
+		return false, err
+	}
+	return DeploymentReady(d, r.strict), nil
+}
+
+func (r *deploymentResource) Watch() (watch.Interface, error) {
+	return r.client.AppsV1().Deployments(r.ns).Watch(nameFieldSelector(r.name))
+}
+
+// DeploymentReady reports whether d has rolled out. With strict set, every
+// replica must be updated and available, matching Kubernetes' own
+// `kubectl rollout status` semantics. Otherwise, a RollingUpdate deployment
+// is considered ready as soon as status.availableReplicas reaches the
+// minimum the rollout guarantees to keep available, i.e.
+// spec.replicas - maxUnavailable.
+func DeploymentReady(d *appsv1.Deployment, strict bool) bool {
+	if d.Status.ObservedGeneration < d.Generation {
+		return false
+	}
+	replicas := int32(1)
+	if d.Spec.Replicas != nil {
+		replicas = *d.Spec.Replicas
+	}
+	if strict || d.Spec.Strategy.Type == appsv1.RecreateDeploymentStrategyType {
+		return d.Status.UpdatedReplicas == replicas && d.Status.AvailableReplicas == replicas
+	}
+	return d.Status.AvailableReplicas >= minAvailableReplicas(d.Spec.Strategy, replicas)
+}
+
+// minAvailableReplicas computes how many replicas a RollingUpdate deployment
+// must keep available, per spec.replicas - maxUnavailable.
+func minAvailableReplicas(strategy appsv1.DeploymentStrategy, replicas int32) int32 {
+	if strategy.RollingUpdate == nil || strategy.RollingUpdate.MaxUnavailable == nil {
+		return replicas
+	}
+	maxUnavailable, err := intstr.GetScaledValueFromIntOrPercent(strategy.RollingUpdate.MaxUnavailable, int(replicas), false)
+	if err != nil || maxUnavailable < 0 {
+		return replicas
+	}
+	if minAvailable := replicas - int32(maxUnavailable); minAvailable > 0 {
+		return minAvailable
+	}
+	return 0
+}
+
+type statefulSetResource struct {
+	client   kubernetes.Interface
+	ns       string
+	name     string
+	deadline time.Duration
+}
+
+func (r *statefulSetResource) String() string { return fmt.Sprintf("statefulset/%s", r.name) }
+func (r *statefulSetResource) Deadline() time.Duration { return r.deadline }
+
+func (r *statefulSetResource) Ready(ctx context.Context) (bool, error) {
+	s, err := r.client.AppsV1().StatefulSets(r.ns).Get(r.name, metav1.GetOptions{})
+	if err != nil {
+		return false, err
+	}
+	return StatefulSetReady(s), nil
+}
+
+func (r *statefulSetResource) Watch() (watch.Interface, error) {
+	return r.client.AppsV1().StatefulSets(r.ns).Watch(nameFieldSelector(r.name))
+}
+
+func StatefulSetReady(s *appsv1.StatefulSet) bool {
+	replicas := int32(1)
+	if s.Spec.Replicas != nil {
+		replicas = *s.Spec.Replicas
+	}
+	if s.Status.UpdatedReplicas != replicas || s.Status.ReadyReplicas != replicas {
+		return false
+	}
+	if s.Spec.UpdateStrategy.Type == appsv1.RollingUpdateStatefulSetStrategyType {
+		return s.Status.CurrentRevision == s.Status.UpdateRevision
+	}
+	return true
+}
+
+type daemonSetResource struct {
+	client   kubernetes.Interface
+	ns       string
+	name     string
+	deadline time.Duration
+}
+
+func (r *daemonSetResource) String() string { return fmt.Sprintf("daemonset/%s", r.name) }
+func (r *daemonSetResource) Deadline() time.Duration { return r.deadline }
+
+func (r *daemonSetResource) Ready(ctx context.Context) (bool, error) {
+	ds, err := r.client.AppsV1().DaemonSets(r.ns).Get(r.name, metav1.GetOptions{})
+	if err != nil {
+		return false, err
+	}
+	return DaemonSetReady(ds), nil
+}
+
+func (r *daemonSetResource) Watch() (watch.Interface, error) {
+	return r.client.AppsV1().DaemonSets(r.ns).Watch(nameFieldSelector(r.name))
+}
+
+func DaemonSetReady(ds *appsv1.DaemonSet) bool {
+	return ds.Status.DesiredNumberScheduled == ds.Status.NumberReady &&
+		ds.Status.UpdatedNumberScheduled == ds.Status.DesiredNumberScheduled
+}
+
+type replicaSetResource struct {
+	client   kubernetes.Interface
+	ns       string
+	name     string
+	deadline time.Duration
+}
+
+func (r *replicaSetResource) String() string { return fmt.Sprintf("replicaset/%s", r.name) }
+func (r *replicaSetResource) Deadline() time.Duration { return r.deadline }
+
+func (r *replicaSetResource) Ready(ctx context.Context) (bool, error) {
+	rs, err := r.client.AppsV1().ReplicaSets(r.ns).Get(r.name, metav1.GetOptions{})
+	if err != nil {
+		return false, err
+	}
+	return ReplicaSetReady(rs), nil
+}
+
+func (r *replicaSetResource) Watch() (watch.Interface, error) {
+	return r.client.AppsV1().ReplicaSets(r.ns).Watch(nameFieldSelector(r.name))
+}
+
+func ReplicaSetReady(rs *appsv1.ReplicaSet) bool {
+	replicas := int32(1)
+	if rs.Spec.Replicas != nil {
+		replicas = *rs.Spec.Replicas
+	}
+	return rs.Status.ReadyReplicas == replicas
+}
+
+type replicationControllerResource struct {
+	client   kubernetes.Interface
+	ns       string
+	name     string
+	deadline time.Duration
+}
+
+func (r *replicationControllerResource) String() string {
+	return fmt.Sprintf("replicationcontroller/%s", r.name)
+}
+func (r *replicationControllerResource) Deadline() time.Duration { return r.deadline }
+
+func (r *replicationControllerResource) Ready(ctx context.Context) (bool, error) {
+	rc, err := r.client.CoreV1().ReplicationControllers(r.ns).Get(r.name, metav1.GetOptions{})
+	if err != nil {
+		return false, err
+	}
+	return ReplicationControllerReady(rc), nil
+}
+
+func (r *replicationControllerResource) Watch() (watch.Interface, error) {
+	return r.client.CoreV1().ReplicationControllers(r.ns).Watch(nameFieldSelector(r.name))
+}
+
+func ReplicationControllerReady(rc *corev1.ReplicationController) bool {
+	replicas := int32(1)
+	if rc.Spec.Replicas != nil {
+		replicas = *rc.Spec.Replicas
+	}
+	return rc.Status.ReadyReplicas == replicas
+}
+
+type podResource struct {
+	client   kubernetes.Interface
+	ns       string
+	name     string
+	deadline time.Duration
+}
+
+func (r *podResource) String() string { return fmt.Sprintf("pod/%s", r.name) }
+func (r *podResource) Deadline() time.Duration { return r.deadline }
+
+func (r *podResource) Ready(ctx context.Context) (bool, error) {
+	p, err := r.client.CoreV1().Pods(r.ns).Get(r.name, metav1.GetOptions{})
+	if err != nil {
+		return false, err
+	}
+	return PodReady(p), nil
+}
+
+func (r *podResource) Watch() (watch.Interface, error) {
+	return r.client.CoreV1().Pods(r.ns).Watch(nameFieldSelector(r.name))
+}
+
+func PodReady(p *corev1.Pod) bool {
+	if p.Status.Phase == corev1.PodSucceeded {
+		return true
+	}
+	if p.Status.Phase != corev1.PodRunning {
+		return false
+	}
+	for _, c := range p.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+type serviceResource struct {
+	client   kubernetes.Interface
+	ns       string
+	name     string
+	deadline time.Duration
+}
+
+func (r *serviceResource) String() string { return fmt.Sprintf("service/%s", r.name) }
+func (r *serviceResource) Deadline() time.Duration { return r.deadline }
+
+func (r *serviceResource) Ready(ctx context.Context) (bool, error) {
+	s, err := r.client.CoreV1().Services(r.ns).Get(r.name, metav1.GetOptions{})
+	if err != nil {
+		return false, err
+	}
+	return ServiceReady(s), nil
+}
+
+func (r *serviceResource) Watch() (watch.Interface, error) {
+	return r.client.CoreV1().Services(r.ns).Watch(nameFieldSelector(r.name))
+}
+
+func ServiceReady(s *corev1.Service) bool {
+	if s.Spec.Type != corev1.ServiceTypeLoadBalancer {
+		return true
+	}
+	return len(s.Status.LoadBalancer.Ingress) > 0
+}
+
+type pvcResource struct {
+	client   kubernetes.Interface
+	ns       string
+	name     string
+	deadline time.Duration
+}
+
+func (r *pvcResource) String() string { return fmt.Sprintf("persistentvolumeclaim/%s", r.name) }
+func (r *pvcResource) Deadline() time.Duration { return r.deadline }
+
+func (r *pvcResource) Ready(ctx context.Context) (bool, error) {
+	pvc, err := r.client.CoreV1().PersistentVolumeClaims(r.ns).Get(r.name, metav1.GetOptions{})
+	if err != nil {
+		return false, err
+	}
+	return pvc.Status.Phase == corev1.ClaimBound, nil
+}
+
+func (r *pvcResource) Watch() (watch.Interface, error) {
+	return r.client.CoreV1().PersistentVolumeClaims(r.ns).Watch(nameFieldSelector(r.name))
+}
+
+type jobResource struct {
+	client   kubernetes.Interface
+	ns       string
+	name     string
+	deadline time.Duration
+}
+
+func (r *jobResource) String() string { return fmt.Sprintf("job/%s", r.name) }
+func (r *jobResource) Deadline() time.Duration { return r.deadline }
+
+func (r *jobResource) Ready(ctx context.Context) (bool, error) {
+	j, err := r.client.BatchV1().Jobs(r.ns).Get(r.name, metav1.GetOptions{})
+	if err != nil {
+		return false, err
+	}
+	return JobReady(j), nil
+}
+
+func (r *jobResource) Watch() (watch.Interface, error) {
+	return r.client.BatchV1().Jobs(r.ns).Watch(nameFieldSelector(r.name))
+}
+
+func JobReady(j *batchv1.Job) bool {
+	completions := int32(1)
+	if j.Spec.Completions != nil {
+		completions = *j.Spec.Completions
+	}
+	return j.Status.Succeeded >= completions
+}
@@ -0,0 +1,437 @@
+/*
+Copyright 2019 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deploy
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	utilpointer "k8s.io/utils/pointer"
+
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestDeploymentReady(t *testing.T) {
+	tests := []struct {
+		description string
+		dep         *appsv1.Deployment
+		strict      bool
+		expected    bool
+	}{
+		{
+			description: "ready",
+			dep: &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Generation: 1},
+				Spec:       appsv1.DeploymentSpec{Replicas: utilpointer.Int32Ptr(3)},
+				Status: appsv1.DeploymentStatus{
+					ObservedGeneration: 1,
+					UpdatedReplicas:    3,
+					AvailableReplicas:  3,
+				},
+			},
+			expected: true,
+		},
+		{
+			description: "stale observed generation",
+			dep: &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Generation: 2},
+				Spec:       appsv1.DeploymentSpec{Replicas: utilpointer.Int32Ptr(3)},
+				Status: appsv1.DeploymentStatus{
+					ObservedGeneration: 1,
+					UpdatedReplicas:    3,
+					AvailableReplicas:  3,
+				},
+			},
+			expected: false,
+		},
+		{
+			description: "strict mode requires all replicas updated",
+			dep: &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Generation: 1},
+				Spec:       appsv1.DeploymentSpec{Replicas: utilpointer.Int32Ptr(3)},
+				Status: appsv1.DeploymentStatus{
+					ObservedGeneration: 1,
+					UpdatedReplicas:    1,
+					AvailableReplicas:  3,
+				},
+			},
+			strict:   true,
+			expected: false,
+		},
+		{
+			description: "maxUnavailable: 1 tolerates one replica short",
+			dep: &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Generation: 1},
+				Spec: appsv1.DeploymentSpec{
+					Replicas: utilpointer.Int32Ptr(3),
+					Strategy: appsv1.DeploymentStrategy{
+						Type: appsv1.RollingUpdateDeploymentStrategyType,
+						RollingUpdate: &appsv1.RollingUpdateDeployment{
+							MaxUnavailable: intOrStringPtr(intstr.FromInt(1)),
+						},
+					},
+				},
+				Status: appsv1.DeploymentStatus{
+					ObservedGeneration: 1,
+					AvailableReplicas:  2,
+				},
+			},
+			expected: true,
+		},
+		{
+			description: "maxUnavailable: 1 not yet within threshold",
+			dep: &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Generation: 1},
+				Spec: appsv1.DeploymentSpec{
+					Replicas: utilpointer.Int32Ptr(3),
+					Strategy: appsv1.DeploymentStrategy{
+						Type: appsv1.RollingUpdateDeploymentStrategyType,
+						RollingUpdate: &appsv1.RollingUpdateDeployment{
+							MaxUnavailable: intOrStringPtr(intstr.FromInt(1)),
+						},
+					},
+				},
+				Status: appsv1.DeploymentStatus{
+					ObservedGeneration: 1,
+					AvailableReplicas:  1,
+				},
+			},
+			expected: false,
+		},
+		{
+			description: `maxUnavailable: "25%" of 4 replicas tolerates one short`,
+			dep: &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Generation: 1},
+				Spec: appsv1.DeploymentSpec{
+					Replicas: utilpointer.Int32Ptr(4),
+					Strategy: appsv1.DeploymentStrategy{
+						Type: appsv1.RollingUpdateDeploymentStrategyType,
+						RollingUpdate: &appsv1.RollingUpdateDeployment{
+							MaxUnavailable: intOrStringPtr(intstr.FromString("25%")),
+						},
+					},
+				},
+				Status: appsv1.DeploymentStatus{
+					ObservedGeneration: 1,
+					AvailableReplicas:  3,
+				},
+			},
+			expected: true,
+		},
+		{
+			description: "recreate strategy still requires every replica available",
+			dep: &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Generation: 1},
+				Spec: appsv1.DeploymentSpec{
+					Replicas: utilpointer.Int32Ptr(3),
+					Strategy: appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType},
+				},
+				Status: appsv1.DeploymentStatus{
+					ObservedGeneration: 1,
+					UpdatedReplicas:    2,
+					AvailableReplicas:  2,
+				},
+			},
+			expected: false,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			t.CheckDeepEqual(test.expected, DeploymentReady(test.dep, test.strict))
+		})
+	}
+}
+
+func intOrStringPtr(v intstr.IntOrString) *intstr.IntOrString {
+	return &v
+}
+
+func TestStatefulSetReady(t *testing.T) {
+	tests := []struct {
+		description string
+		sts         *appsv1.StatefulSet
+		expected    bool
+	}{
+		{
+			description: "rolling update, revisions match",
+			sts: &appsv1.StatefulSet{
+				Spec: appsv1.StatefulSetSpec{
+					Replicas:       utilpointer.Int32Ptr(2),
+					UpdateStrategy: appsv1.StatefulSetUpdateStrategy{Type: appsv1.RollingUpdateStatefulSetStrategyType},
+				},
+				Status: appsv1.StatefulSetStatus{
+					UpdatedReplicas: 2,
+					ReadyReplicas:   2,
+					CurrentRevision: "sts-1",
+					UpdateRevision:  "sts-1",
+				},
+			},
+			expected: true,
+		},
+		{
+			description: "rolling update, revisions differ",
+			sts: &appsv1.StatefulSet{
+				Spec: appsv1.StatefulSetSpec{
+					Replicas:       utilpointer.Int32Ptr(2),
+					UpdateStrategy: appsv1.StatefulSetUpdateStrategy{Type: appsv1.RollingUpdateStatefulSetStrategyType},
+				},
+				Status: appsv1.StatefulSetStatus{
+					UpdatedReplicas: 2,
+					ReadyReplicas:   2,
+					CurrentRevision: "sts-1",
+					UpdateRevision:  "sts-2",
+				},
+			},
+			expected: false,
+		},
+		{
+			description: "on delete, revisions differ but replicas ready",
+			sts: &appsv1.StatefulSet{
+				Spec: appsv1.StatefulSetSpec{
+					Replicas:       utilpointer.Int32Ptr(2),
+					UpdateStrategy: appsv1.StatefulSetUpdateStrategy{Type: appsv1.OnDeleteStatefulSetStrategyType},
+				},
+				Status: appsv1.StatefulSetStatus{
+					UpdatedReplicas: 2,
+					ReadyReplicas:   2,
+					CurrentRevision: "sts-1",
+					UpdateRevision:  "sts-2",
+				},
+			},
+			expected: true,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			t.CheckDeepEqual(test.expected, StatefulSetReady(test.sts))
+		})
+	}
+}
+
+func TestDaemonSetReady(t *testing.T) {
+	tests := []struct {
+		description string
+		ds          *appsv1.DaemonSet
+		expected    bool
+	}{
+		{
+			description: "ready",
+			ds: &appsv1.DaemonSet{
+				Status: appsv1.DaemonSetStatus{
+					DesiredNumberScheduled: 3,
+					NumberReady:            3,
+					UpdatedNumberScheduled: 3,
+				},
+			},
+			expected: true,
+		},
+		{
+			description: "rollout in progress",
+			ds: &appsv1.DaemonSet{
+				Status: appsv1.DaemonSetStatus{
+					DesiredNumberScheduled: 3,
+					NumberReady:            2,
+					UpdatedNumberScheduled: 2,
+				},
+			},
+			expected: false,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			t.CheckDeepEqual(test.expected, DaemonSetReady(test.ds))
+		})
+	}
+}
+
+func TestReplicaSetReady(t *testing.T) {
+	tests := []struct {
+		description string
+		rs          *appsv1.ReplicaSet
+		expected    bool
+	}{
+		{
+			description: "ready",
+			rs: &appsv1.ReplicaSet{
+				Spec:   appsv1.ReplicaSetSpec{Replicas: utilpointer.Int32Ptr(3)},
+				Status: appsv1.ReplicaSetStatus{ReadyReplicas: 3},
+			},
+			expected: true,
+		},
+		{
+			description: "not yet ready",
+			rs: &appsv1.ReplicaSet{
+				Spec:   appsv1.ReplicaSetSpec{Replicas: utilpointer.Int32Ptr(3)},
+				Status: appsv1.ReplicaSetStatus{ReadyReplicas: 2},
+			},
+			expected: false,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			t.CheckDeepEqual(test.expected, ReplicaSetReady(test.rs))
+		})
+	}
+}
+
+func TestReplicationControllerReady(t *testing.T) {
+	tests := []struct {
+		description string
+		rc          *corev1.ReplicationController
+		expected    bool
+	}{
+		{
+			description: "ready",
+			rc: &corev1.ReplicationController{
+				Spec:   corev1.ReplicationControllerSpec{Replicas: utilpointer.Int32Ptr(3)},
+				Status: corev1.ReplicationControllerStatus{ReadyReplicas: 3},
+			},
+			expected: true,
+		},
+		{
+			description: "not yet ready",
+			rc: &corev1.ReplicationController{
+				Spec:   corev1.ReplicationControllerSpec{Replicas: utilpointer.Int32Ptr(3)},
+				Status: corev1.ReplicationControllerStatus{ReadyReplicas: 2},
+			},
+			expected: false,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			t.CheckDeepEqual(test.expected, ReplicationControllerReady(test.rc))
+		})
+	}
+}
+
+func TestPodReady(t *testing.T) {
+	tests := []struct {
+		description string
+		pod         *corev1.Pod
+		expected    bool
+	}{
+		{
+			description: "running and ready",
+			pod: &corev1.Pod{
+				Status: corev1.PodStatus{
+					Phase:      corev1.PodRunning,
+					Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+				},
+			},
+			expected: true,
+		},
+		{
+			description: "running but not ready",
+			pod: &corev1.Pod{
+				Status: corev1.PodStatus{
+					Phase:      corev1.PodRunning,
+					Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionFalse}},
+				},
+			},
+			expected: false,
+		},
+		{
+			description: "succeeded one-shot pod",
+			pod: &corev1.Pod{
+				Status: corev1.PodStatus{Phase: corev1.PodSucceeded},
+			},
+			expected: true,
+		},
+		{
+			description: "pending",
+			pod: &corev1.Pod{
+				Status: corev1.PodStatus{Phase: corev1.PodPending},
+			},
+			expected: false,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			t.CheckDeepEqual(test.expected, PodReady(test.pod))
+		})
+	}
+}
+
+func TestServiceReady(t *testing.T) {
+	tests := []struct {
+		description string
+		svc         *corev1.Service
+		expected    bool
+	}{
+		{
+			description: "ClusterIP is ready immediately",
+			svc:         &corev1.Service{Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeClusterIP}},
+			expected:    true,
+		},
+		{
+			description: "LoadBalancer without ingress",
+			svc:         &corev1.Service{Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer}},
+			expected:    false,
+		},
+		{
+			description: "LoadBalancer with ingress",
+			svc: &corev1.Service{
+				Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer},
+				Status: corev1.ServiceStatus{
+					LoadBalancer: corev1.LoadBalancerStatus{
+						Ingress: []corev1.LoadBalancerIngress{{IP: "1.2.3.4"}},
+					},
+				},
+			},
+			expected: true,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			t.CheckDeepEqual(test.expected, ServiceReady(test.svc))
+		})
+	}
+}
+
+func TestJobReady(t *testing.T) {
+	tests := []struct {
+		description string
+		job         *batchv1.Job
+		expected    bool
+	}{
+		{
+			description: "completions reached",
+			job: &batchv1.Job{
+				Spec:   batchv1.JobSpec{Completions: utilpointer.Int32Ptr(2)},
+				Status: batchv1.JobStatus{Succeeded: 2},
+			},
+			expected: true,
+		},
+		{
+			description: "completions not reached",
+			job: &batchv1.Job{
+				Spec:   batchv1.JobSpec{Completions: utilpointer.Int32Ptr(2)},
+				Status: batchv1.JobStatus{Succeeded: 1},
+			},
+			expected: false,
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			t.CheckDeepEqual(test.expected, JobReady(test.job))
+		})
+	}
+}
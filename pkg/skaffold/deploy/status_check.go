@@ -0,0 +1,320 @@
+/*
+Copyright 2019 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubectl"
+)
+
+var defaultPollPeriodInMilliseconds = 200
+
+const tabHeader = " -"
+
+// kubectlRolloutKinds are the kinds `kubectl rollout status` knows how to
+// watch natively. Every other kind falls back to polling its Ready() predicate.
+var kubectlRolloutKinds = map[string]bool{
+	"deployment":  true,
+	"statefulset": true,
+	"daemonset":   true,
+}
+
+// StatusCheckOptions bundles the knobs that shape how StatusCheck waits for
+// resources to become ready.
+type StatusCheckOptions struct {
+	// Deadline is the default amount of time to wait for a resource to
+	// become ready, for resources that don't specify their own (e.g. a
+	// Deployment's progressDeadlineSeconds).
+	Deadline time.Duration
+	// Mode selects whether resources are observed through client-go watches
+	// (the default) or through polling `kubectl rollout status`.
+	Mode StatusCheckMode
+	// StrictReplicas requires every Deployment replica to be updated and
+	// available before a Deployment is considered ready, ignoring
+	// maxUnavailable. Corresponds to the statusCheck.strictReplicas config.
+	StrictReplicas bool
+}
+
+// StatusCheck waits for every resource Skaffold deployed in this run to
+// become ready, printing progress as it goes.
+func StatusCheck(ctx context.Context, client kubernetes.Interface, cli *kubectl.CLI, ns string, labeller *Labeller, opts StatusCheckOptions, out io.Writer) error {
+	resources, err := getResources(client, ns, labeller, opts.Deadline, opts.StrictReplicas)
+	if err != nil {
+		return errors.Wrap(err, "could not fetch resources")
+	}
+
+	var wg sync.WaitGroup
+	c := newCounter(len(resources))
+	syncMap := &sync.Map{}
+	for _, r := range resources {
+		wg.Add(1)
+		go func(r Resource) {
+			defer wg.Done()
+			err := pollResourceRolloutStatus(ctx, cli, r, opts.Mode)
+			syncMap.Store(r.String(), err)
+			pending := c.markProcessed()
+			printStatusCheckSummary(r.String(), c, int(pending), err, out)
+		}(r)
+	}
+	wg.Wait()
+
+	return getSkaffoldDeployStatus(syncMap)
+}
+
+// getResources lists every Skaffold-deployed resource, across every kind we
+// know how to status-check, tagged with labeller's run ID.
+func getResources(client kubernetes.Interface, ns string, labeller *Labeller, deadlineDuration time.Duration, strictReplicas bool) ([]Resource, error) {
+	opts := metav1.ListOptions{LabelSelector: labeller.RunIDSelector()}
+
+	var resources []Resource
+
+	deps, err := client.AppsV1().Deployments(ns).List(opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not fetch deployments")
+	}
+	for i := range deps.Items {
+		d := deps.Items[i]
+		resources = append(resources, &deploymentResource{
+			client: client, ns: ns, name: d.Name,
+			deadline: progressDeadline(d.Spec.ProgressDeadlineSeconds, deadlineDuration),
+			strict:   strictReplicas,
+		})
+	}
+
+	sts, err := client.AppsV1().StatefulSets(ns).List(opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not fetch statefulsets")
+	}
+	for i := range sts.Items {
+		s := sts.Items[i]
+		resources = append(resources, &statefulSetResource{client: client, ns: ns, name: s.Name, deadline: deadlineDuration})
+	}
+
+	dss, err := client.AppsV1().DaemonSets(ns).List(opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not fetch daemonsets")
+	}
+	for i := range dss.Items {
+		d := dss.Items[i]
+		resources = append(resources, &daemonSetResource{client: client, ns: ns, name: d.Name, deadline: deadlineDuration})
+	}
+
+	rss, err := client.AppsV1().ReplicaSets(ns).List(opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not fetch replicasets")
+	}
+	for i := range rss.Items {
+		r := rss.Items[i]
+		resources = append(resources, &replicaSetResource{client: client, ns: ns, name: r.Name, deadline: deadlineDuration})
+	}
+
+	rcs, err := client.CoreV1().ReplicationControllers(ns).List(opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not fetch replicationcontrollers")
+	}
+	for i := range rcs.Items {
+		r := rcs.Items[i]
+		resources = append(resources, &replicationControllerResource{client: client, ns: ns, name: r.Name, deadline: deadlineDuration})
+	}
+
+	pods, err := client.CoreV1().Pods(ns).List(opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not fetch pods")
+	}
+	for i := range pods.Items {
+		p := pods.Items[i]
+		resources = append(resources, &podResource{client: client, ns: ns, name: p.Name, deadline: deadlineDuration})
+	}
+
+	svcs, err := client.CoreV1().Services(ns).List(opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not fetch services")
+	}
+	for i := range svcs.Items {
+		s := svcs.Items[i]
+		resources = append(resources, &serviceResource{client: client, ns: ns, name: s.Name, deadline: deadlineDuration})
+	}
+
+	pvcs, err := client.CoreV1().PersistentVolumeClaims(ns).List(opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not fetch persistentvolumeclaims")
+	}
+	for i := range pvcs.Items {
+		p := pvcs.Items[i]
+		resources = append(resources, &pvcResource{client: client, ns: ns, name: p.Name, deadline: deadlineDuration})
+	}
+
+	jobs, err := client.BatchV1().Jobs(ns).List(opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not fetch jobs")
+	}
+	for i := range jobs.Items {
+		j := jobs.Items[i]
+		resources = append(resources, &jobResource{client: client, ns: ns, name: j.Name, deadline: deadlineDuration})
+	}
+
+	return resources, nil
+}
+
+// progressDeadline returns the smaller of the resource's own
+// progressDeadlineSeconds and the global status-check deadline.
+func progressDeadline(progressDeadlineSeconds *int32, globalDeadline time.Duration) time.Duration {
+	if progressDeadlineSeconds == nil || time.Duration(*progressDeadlineSeconds)*time.Second > globalDeadline {
+		return globalDeadline
+	}
+	return time.Duration(*progressDeadlineSeconds) * time.Second
+}
+
+// pollResourceRolloutStatus waits for r to become ready, an error occurs, or
+// its deadline is reached. In StatusCheckModeWatch (the default) this uses
+// a client-go watch; in StatusCheckModeKubectl, kinds `kubectl rollout
+// status` understands are watched through the kubectl CLI and every other
+// kind falls back to polling r.Ready().
+func pollResourceRolloutStatus(ctx context.Context, cli *kubectl.CLI, r Resource, mode StatusCheckMode) error {
+	if mode == StatusCheckModeKubectl {
+		kind := resourceKind(r)
+		if kubectlRolloutKinds[kind] {
+			return pollRolloutStatus(ctx, cli, kind, resourceName(r), r.Deadline())
+		}
+		return pollResourceReady(ctx, r)
+	}
+	return watchResourceStatus(ctx, r)
+}
+
+func resourceKind(r Resource) string {
+	return strings.SplitN(r.String(), "/", 2)[0]
+}
+
+func resourceName(r Resource) string {
+	return strings.SplitN(r.String(), "/", 2)[1]
+}
+
+func pollResourceReady(ctx context.Context, r Resource) error {
+	pollDuration := time.Duration(defaultPollPeriodInMilliseconds) * time.Millisecond
+	ticker := time.NewTicker(pollDuration)
+	defer ticker.Stop()
+
+	ctx, cancel := context.WithTimeout(ctx, r.Deadline())
+	defer cancel()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return errors.Wrap(ctx.Err(), "could not stabilize within deadline")
+		case <-ticker.C:
+			ready, err := r.Ready(ctx)
+			if err != nil {
+				return err
+			}
+			if ready {
+				return nil
+			}
+		}
+	}
+}
+
+// pollRolloutStatus polls `kubectl rollout status` for kind/name until it
+// reports success, errors out, or deadline is exceeded.
+func pollRolloutStatus(ctx context.Context, cli *kubectl.CLI, kind, name string, deadline time.Duration) error {
+	pollDuration := time.Duration(defaultPollPeriodInMilliseconds) * time.Millisecond
+	ticker := time.NewTicker(pollDuration)
+	defer ticker.Stop()
+
+	ctx, cancel := context.WithTimeout(ctx, deadline)
+	defer cancel()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return errors.Wrap(ctx.Err(), "could not stabilize within deadline")
+		default:
+			status, err := getRollOutStatus(ctx, cli, kind, name)
+			if err != nil {
+				return err
+			}
+			if strings.Contains(status, "successfully rolled out") {
+				return nil
+			}
+		}
+		<-ticker.C
+	}
+}
+
+// getRollOutStatus shells out to `kubectl rollout status` and returns its output.
+func getRollOutStatus(ctx context.Context, cli *kubectl.CLI, kind, name string) (string, error) {
+	b, err := cli.RunOut(ctx, "rollout", "status", kind, name, "--watch=false")
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func getSkaffoldDeployStatus(m *sync.Map) error {
+	errorStrings := []string{}
+	m.Range(func(k, v interface{}) bool {
+		if err, ok := v.(error); ok && err != nil {
+			errorStrings = append(errorStrings, fmt.Sprintf("%s failed due to %s", k, err.Error()))
+		}
+		return true
+	})
+
+	if len(errorStrings) == 0 {
+		return nil
+	}
+	return fmt.Errorf("following resources failed the status check:\n%s", strings.Join(errorStrings, "\n"))
+}
+
+func printStatusCheckSummary(resourceName string, c *counter, pending int, err error, out io.Writer) {
+	status := fmt.Sprintf("%s %s", tabHeader, resourceName)
+	if err != nil {
+		status = fmt.Sprintf("%s failed.%s Error: %s.\n", status, c.getPendingMessage(int32(pending)), err.Error())
+	} else {
+		status = fmt.Sprintf("%s is ready.%s\n", status, c.getPendingMessage(int32(pending)))
+	}
+	fmt.Fprint(out, status)
+}
+
+type counter struct {
+	total   int
+	pending int32
+}
+
+func newCounter(i int) *counter {
+	return &counter{total: i, pending: int32(i)}
+}
+
+func (c *counter) markProcessed() int32 {
+	return atomic.AddInt32(&c.pending, -1)
+}
+
+func (c *counter) getPendingMessage(pending int32) string {
+	if int(pending) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" [%d/%d deployment(s) still pending]", pending, c.total)
+}
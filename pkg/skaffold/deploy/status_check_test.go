@@ -28,6 +28,7 @@ import (
 	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	fakekubeclientset "k8s.io/client-go/kubernetes/fake"
@@ -38,11 +39,12 @@ import (
 	"github.com/GoogleContainerTools/skaffold/testutil"
 )
 
-func TestGetDeployments(t *testing.T) {
+func TestGetResources(t *testing.T) {
 	labeller := NewLabeller("")
 	tests := []struct {
 		description string
 		deps        []*appsv1.Deployment
+		pods        []*corev1.Pod
 		expected    map[string]time.Duration
 		shouldErr   bool
 	}{
@@ -71,7 +73,7 @@ func TestGetDeployments(t *testing.T) {
 					Spec: appsv1.DeploymentSpec{ProgressDeadlineSeconds: utilpointer.Int32Ptr(20)},
 				},
 			},
-			expected: map[string]time.Duration{"dep1": time.Duration(10) * time.Second, "dep2": time.Duration(20) * time.Second},
+			expected: map[string]time.Duration{"deployment/dep1": time.Duration(10) * time.Second, "deployment/dep2": time.Duration(20) * time.Second},
 		}, {
 			description: "command flag deadline is less than deployment spec.",
 			deps: []*appsv1.Deployment{
@@ -87,7 +89,7 @@ func TestGetDeployments(t *testing.T) {
 					Spec: appsv1.DeploymentSpec{ProgressDeadlineSeconds: utilpointer.Int32Ptr(300)},
 				},
 			},
-			expected: map[string]time.Duration{"dep1": time.Duration(200) * time.Second},
+			expected: map[string]time.Duration{"deployment/dep1": time.Duration(200) * time.Second},
 		}, {
 			description: "multiple deployments with no progress deadline set",
 			deps: []*appsv1.Deployment{
@@ -111,11 +113,11 @@ func TestGetDeployments(t *testing.T) {
 					},
 				},
 			},
-			expected: map[string]time.Duration{"dep1": time.Duration(100) * time.Second,
-				"dep2": time.Duration(200) * time.Second},
+			expected: map[string]time.Duration{"deployment/dep1": time.Duration(100) * time.Second,
+				"deployment/dep2": time.Duration(200) * time.Second},
 		},
 		{
-			description: "no deployments",
+			description: "no resources",
 			expected:    map[string]time.Duration{},
 		},
 		{
@@ -142,7 +144,7 @@ func TestGetDeployments(t *testing.T) {
 					Spec: appsv1.DeploymentSpec{ProgressDeadlineSeconds: utilpointer.Int32Ptr(100)},
 				},
 			},
-			expected: map[string]time.Duration{"dep1": time.Duration(100) * time.Second},
+			expected: map[string]time.Duration{"deployment/dep1": time.Duration(100) * time.Second},
 		},
 		{
 			description: "deployment in correct namespace but not deployed by skaffold",
@@ -176,22 +178,56 @@ func TestGetDeployments(t *testing.T) {
 			},
 			expected: map[string]time.Duration{},
 		},
+		{
+			description: "deployment and pod tracked together",
+			deps: []*appsv1.Deployment{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "dep1",
+						Namespace: "test",
+						Labels:    map[string]string{RunIDLabel: labeller.runID},
+					},
+					Spec: appsv1.DeploymentSpec{ProgressDeadlineSeconds: utilpointer.Int32Ptr(100)},
+				},
+			},
+			pods: []*corev1.Pod{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "pod1",
+						Namespace: "test",
+						Labels:    map[string]string{RunIDLabel: labeller.runID},
+					},
+				},
+			},
+			expected: map[string]time.Duration{
+				"deployment/dep1": time.Duration(100) * time.Second,
+				"pod/pod1":        time.Duration(200) * time.Second,
+			},
+		},
 	}
 
 	for _, test := range tests {
 		testutil.Run(t, test.description, func(t *testutil.T) {
-			objs := make([]runtime.Object, len(test.deps))
-			for i, dep := range test.deps {
-				objs[i] = dep
+			var objs []runtime.Object
+			for _, dep := range test.deps {
+				objs = append(objs, dep)
+			}
+			for _, pod := range test.pods {
+				objs = append(objs, pod)
 			}
 			client := fakekubeclientset.NewSimpleClientset(objs...)
-			actual, err := getDeployments(client, "test", labeller, time.Duration(200)*time.Second)
+			resources, err := getResources(client, "test", labeller, time.Duration(200)*time.Second, false)
+
+			actual := map[string]time.Duration{}
+			for _, r := range resources {
+				actual[r.String()] = r.Deadline()
+			}
 			t.CheckErrorAndDeepEqual(test.shouldErr, err, test.expected, actual)
 		})
 	}
 }
 
-func TestPollDeploymentRolloutStatus(t *testing.T) {
+func TestPollRolloutStatus(t *testing.T) {
 	rolloutCmd := "kubectl --context kubecontext --namespace test rollout status deployment dep --watch=false"
 	tests := []struct {
 		description string
@@ -230,7 +266,7 @@ func TestPollDeploymentRolloutStatus(t *testing.T) {
 			t.Override(&util.DefaultExecCommand, test.commands)
 
 			cli := &kubectl.CLI{KubeContext: testKubeContext, Namespace: "test"}
-			err := pollDeploymentRolloutStatus(context.Background(), cli, "dep", time.Duration(test.duration)*time.Millisecond)
+			err := pollRolloutStatus(context.Background(), cli, "deployment", "dep", time.Duration(test.duration)*time.Millisecond)
 			t.CheckError(test.shouldErr, err)
 		})
 	}
@@ -246,28 +282,28 @@ func TestGetDeployStatus(t *testing.T) {
 		{
 			description: "one error",
 			deps: map[string]interface{}{
-				"dep1": "SUCCESS",
-				"dep2": fmt.Errorf("could not return within default timeout"),
+				"deployment/dep1": "SUCCESS",
+				"deployment/dep2": fmt.Errorf("could not return within default timeout"),
 			},
-			expectedErrMsg: []string{"deployment dep2 failed due to could not return within default timeout"},
+			expectedErrMsg: []string{"deployment/dep2 failed due to could not return within default timeout"},
 			shouldErr:      true,
 		},
 		{
 			description: "no error",
 			deps: map[string]interface{}{
-				"dep1": "SUCCESS",
-				"dep2": "RUNNING",
+				"deployment/dep1": "SUCCESS",
+				"deployment/dep2": "RUNNING",
 			},
 		},
 		{
 			description: "multiple errors",
 			deps: map[string]interface{}{
-				"dep1": "SUCCESS",
-				"dep2": fmt.Errorf("could not return within default timeout"),
-				"dep3": fmt.Errorf("ERROR"),
+				"deployment/dep1": "SUCCESS",
+				"deployment/dep2": fmt.Errorf("could not return within default timeout"),
+				"deployment/dep3": fmt.Errorf("ERROR"),
 			},
-			expectedErrMsg: []string{"deployment dep2 failed due to could not return within default timeout",
-				"deployment dep3 failed due to ERROR"},
+			expectedErrMsg: []string{"deployment/dep2 failed due to could not return within default timeout",
+				"deployment/dep3 failed due to ERROR"},
 			shouldErr: true,
 		},
 	}
@@ -326,7 +362,7 @@ func TestGetRollOutStatus(t *testing.T) {
 			t.Override(&util.DefaultExecCommand, test.commands)
 
 			cli := &kubectl.CLI{KubeContext: testKubeContext, Namespace: "test"}
-			actual, err := getRollOutStatus(context.Background(), cli, "dep")
+			actual, err := getRollOutStatus(context.Background(), cli, "deployment", "dep")
 
 			t.CheckErrorAndDeepEqual(test.shouldErr, err, test.expected, actual)
 		})
@@ -335,34 +371,46 @@ func TestGetRollOutStatus(t *testing.T) {
 
 func TestPrintSummaryStatus(t *testing.T) {
 	tests := []struct {
-		description string
-		pending     int32
-		err         error
-		expected    string
+		description  string
+		resourceName string
+		pending      int32
+		err          error
+		expected     string
 	}{
 		{
-			description: "no deployment left and current is in success",
-			pending:     0,
-			err:         nil,
-			expected:    " - deployment/dep is ready.\n",
+			description:  "no resources left and current is in success",
+			resourceName: "deployment/dep",
+			pending:      0,
+			err:          nil,
+			expected:     " - deployment/dep is ready.\n",
+		},
+		{
+			description:  "no resources left and current is in error",
+			resourceName: "deployment/dep",
+			pending:      0,
+			err:          errors.New("context deadline expired"),
+			expected:     " - deployment/dep failed. Error: context deadline expired.\n",
 		},
 		{
-			description: "no deployment left and current is in error",
-			pending:     0,
-			err:         errors.New("context deadline expired"),
-			expected:    " - deployment/dep failed. Error: context deadline expired.\n",
+			description:  "more than 1 resource left and current is in success",
+			resourceName: "deployment/dep",
+			pending:      4,
+			err:          nil,
+			expected:     " - deployment/dep is ready. [4/10 deployment(s) still pending]\n",
 		},
 		{
-			description: "more than 1 deployment left and current is in success",
-			pending:     4,
-			err:         nil,
-			expected:    " - deployment/dep is ready. [4/10 deployment(s) still pending]\n",
+			description:  "more than 1 resource left and current is in error",
+			resourceName: "deployment/dep",
+			pending:      8,
+			err:          errors.New("context deadline expired"),
+			expected:     " - deployment/dep failed. [8/10 deployment(s) still pending] Error: context deadline expired.\n",
 		},
 		{
-			description: "more than 1 deployment left and current is in error",
-			pending:     8,
-			err:         errors.New("context deadline expired"),
-			expected:    " - deployment/dep failed. [8/10 deployment(s) still pending] Error: context deadline expired.\n",
+			description:  "kind-qualified name for a non-deployment resource",
+			resourceName: "statefulset/foo",
+			pending:      0,
+			err:          nil,
+			expected:     " - statefulset/foo is ready.\n",
 		},
 	}
 
@@ -373,7 +421,7 @@ func TestPrintSummaryStatus(t *testing.T) {
 				total:   10,
 				pending: test.pending,
 			}
-			printStatusCheckSummary("dep", c, int(test.pending), test.err, out)
+			printStatusCheckSummary(test.resourceName, c, int(test.pending), test.err, out)
 			t.CheckDeepEqual(test.expected, out.String())
 		})
 	}
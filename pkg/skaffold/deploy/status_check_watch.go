@@ -0,0 +1,78 @@
+/*
+Copyright 2019 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deploy
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// StatusCheckMode selects how status-check observes resource rollouts.
+type StatusCheckMode string
+
+const (
+	// StatusCheckModeWatch opens a client-go watch per resource and reacts
+	// to typed status updates as they stream in. This is the default.
+	StatusCheckModeWatch StatusCheckMode = "watch"
+	// StatusCheckModeKubectl shells out to `kubectl rollout status` on a
+	// fixed poll interval, kept for backwards compatibility.
+	StatusCheckModeKubectl StatusCheckMode = "kubectl"
+)
+
+// watchResourceStatus waits for r to become ready by watching it directly
+// through the typed clientset, falling back to periodic Get calls if the
+// watch connection is unavailable or drops.
+func watchResourceStatus(ctx context.Context, r Resource) error {
+	ctx, cancel := context.WithTimeout(ctx, r.Deadline())
+	defer cancel()
+
+	w, err := r.Watch()
+	if err != nil {
+		return pollResourceReady(ctx, r)
+	}
+	defer w.Stop()
+
+	lastMessage := ""
+	for {
+		select {
+		case <-ctx.Done():
+			if lastMessage != "" {
+				return errors.Wrap(ctx.Err(), lastMessage)
+			}
+			return errors.Wrap(ctx.Err(), "could not stabilize within deadline")
+		case event, ok := <-w.ResultChan():
+			if !ok {
+				// The watch connection dropped; fall back to polling for
+				// the remainder of the deadline.
+				return pollResourceReady(ctx, r)
+			}
+			strict := false
+			if d, ok := r.(*deploymentResource); ok {
+				strict = d.strict
+			}
+			ready, message, err := readyAndMessage(event.Object, strict)
+			if err != nil {
+				return err
+			}
+			if ready {
+				return nil
+			}
+			lastMessage = message
+		}
+	}
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2019 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deploy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	fakekubeclientset "k8s.io/client-go/kubernetes/fake"
+	kubetesting "k8s.io/client-go/testing"
+	utilpointer "k8s.io/utils/pointer"
+
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestWatchResourceStatus(t *testing.T) {
+	tests := []struct {
+		description string
+		events      []*appsv1.Deployment
+		shouldErr   bool
+	}{
+		{
+			description: "deployment becomes ready on a later event",
+			events: []*appsv1.Deployment{
+				notReadyDeployment("dep", 1, 3),
+				notReadyDeployment("dep", 2, 3),
+				readyDeployment("dep", 3),
+			},
+		},
+		{
+			description: "deployment never becomes ready before the deadline",
+			events: []*appsv1.Deployment{
+				notReadyDeployment("dep", 1, 3),
+			},
+			shouldErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			fakeWatch := watch.NewFake()
+			client := fakekubeclientset.NewSimpleClientset()
+			client.PrependWatchReactor("deployments", kubetesting.DefaultWatchReactor(fakeWatch, nil))
+
+			go func() {
+				for _, d := range test.events {
+					fakeWatch.Modify(d)
+				}
+			}()
+
+			r := &deploymentResource{client: client, ns: "test", name: "dep", deadline: 50 * time.Millisecond}
+			err := watchResourceStatus(context.Background(), r)
+			t.CheckError(test.shouldErr, err)
+		})
+	}
+}
+
+func TestWatchResourceStatusReplicaSet(t *testing.T) {
+	tests := []struct {
+		description string
+		events      []*appsv1.ReplicaSet
+		shouldErr   bool
+	}{
+		{
+			description: "replicaset becomes ready on a later event",
+			events: []*appsv1.ReplicaSet{
+				notReadyReplicaSet("rs", 1, 3),
+				readyReplicaSet("rs", 3),
+			},
+		},
+		{
+			description: "replicaset never becomes ready before the deadline",
+			events: []*appsv1.ReplicaSet{
+				notReadyReplicaSet("rs", 1, 3),
+			},
+			shouldErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			fakeWatch := watch.NewFake()
+			client := fakekubeclientset.NewSimpleClientset()
+			client.PrependWatchReactor("replicasets", kubetesting.DefaultWatchReactor(fakeWatch, nil))
+
+			go func() {
+				for _, rs := range test.events {
+					fakeWatch.Modify(rs)
+				}
+			}()
+
+			r := &replicaSetResource{client: client, ns: "test", name: "rs", deadline: 50 * time.Millisecond}
+			err := watchResourceStatus(context.Background(), r)
+			t.CheckError(test.shouldErr, err)
+		})
+	}
+}
+
+func readyReplicaSet(name string, replicas int32) *appsv1.ReplicaSet {
+	return &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       appsv1.ReplicaSetSpec{Replicas: utilpointer.Int32Ptr(replicas)},
+		Status:     appsv1.ReplicaSetStatus{ReadyReplicas: replicas},
+	}
+}
+
+func notReadyReplicaSet(name string, ready, replicas int32) *appsv1.ReplicaSet {
+	return &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       appsv1.ReplicaSetSpec{Replicas: utilpointer.Int32Ptr(replicas)},
+		Status:     appsv1.ReplicaSetStatus{ReadyReplicas: ready},
+	}
+}
+
+func readyDeployment(name string, replicas int32) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Generation: 1},
+		Spec:       appsv1.DeploymentSpec{Replicas: utilpointer.Int32Ptr(replicas)},
+		Status: appsv1.DeploymentStatus{
+			ObservedGeneration: 1,
+			UpdatedReplicas:    replicas,
+			AvailableReplicas:  replicas,
+		},
+	}
+}
+
+func notReadyDeployment(name string, available, replicas int32) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Generation: 1},
+		Spec:       appsv1.DeploymentSpec{Replicas: utilpointer.Int32Ptr(replicas)},
+		Status: appsv1.DeploymentStatus{
+			ObservedGeneration: 1,
+			UpdatedReplicas:    available,
+			AvailableReplicas:  available,
+		},
+	}
+}